@@ -1,5 +1,7 @@
 use criterion::{criterion_group, criterion_main, BenchmarkGroup, Criterion};
-use frodo_pir::api::{CommonParams, QueryParams, Response, Shard};
+use frodo_pir::api::{
+  recommended_plaintext_bits, CommonParams, QueryParams, Response, Shard,
+};
 use pi_rs_cli_utils::*;
 use std::time::Duration;
 
@@ -7,13 +9,15 @@ const BENCH_ONLINE: bool = true;
 const BENCH_DB_GEN: bool = true;
 
 fn criterion_benchmark(c: &mut Criterion) {
+  let flags = parse_from_env();
+  let plaintext_bits =
+    flags.plaintext_bits_or(recommended_plaintext_bits(flags.matrix_height));
   let CLIFlags {
     matrix_height,
     lwe_dim,
     elem_size,
-    plaintext_bits,
     ..
-  } = parse_from_env();
+  } = flags;
   let mut lwe_group = c.benchmark_group("lwe");
 
   println!("Setting up DB for benchmarking. This might take a while...");
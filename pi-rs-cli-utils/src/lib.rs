@@ -1,4 +1,5 @@
-use clap::{arg, Parser};
+use clap::error::ErrorKind;
+use clap::{arg, CommandFactory, Parser};
 use std::env;
 use std::num::ParseIntError;
 
@@ -11,14 +12,36 @@ pub struct CLIFlags {
   /// LWE dimension
   #[arg(short = 'd', long = "dim", default_value_t = 2048)]
   pub lwe_dim: usize,
-  /// Number of plaintext bits encoded in each entry of DB matrix
-  #[arg(short, long, default_value_t = 10)]
-  pub plaintext_bits: usize,
+  /// Number of plaintext bits encoded in each entry of DB matrix (derived
+  /// from the height of DB matrix if unset)
+  #[arg(short, long)]
+  pub plaintext_bits: Option<usize>,
   /// Log2 of element bit length
   #[arg(short, long, default_value_t = 13, value_parser = parse_exp_to_usize)]
   pub elem_size: usize,
 }
 
+impl CLIFlags {
+  /// Returns the plaintext bits if set, or else the value derived by the
+  /// caller from the height of DB matrix (e.g. using
+  /// `frodo_pir::api::recommended_plaintext_bits`). Exits with a usage
+  /// error if neither is available.
+  pub fn plaintext_bits_or(&self, derived: Option<usize>) -> usize {
+    match self.plaintext_bits.or(derived) {
+      Some(bits) => bits,
+      None => CLIFlags::command()
+        .error(
+          ErrorKind::ValueValidation,
+          format!(
+            "No plaintext bits known for matrix height: {}, set them explicitly",
+            self.matrix_height
+          ),
+        )
+        .exit(),
+    }
+  }
+}
+
 pub fn parse_cli_flags() -> CLIFlags {
   CLIFlags::parse()
 }
@@ -27,10 +50,12 @@ pub fn parse_from_env() -> CLIFlags {
   let elem_size =
     parse_exp_to_usize(&env::var("PIR_ELEM_SIZE_EXP").unwrap()).unwrap();
   let lwe_dim: usize = env::var("PIR_LWE_DIM").unwrap().parse().unwrap();
-  let plaintext_bits: usize =
-    env::var("PIR_PLAINTEXT_BITS").unwrap().parse().unwrap();
   let matrix_height =
     parse_exp_to_usize(&env::var("PIR_MATRIX_HEIGHT_EXP").unwrap()).unwrap();
+  // if unset, the plaintext bits are left for the caller to derive
+  let plaintext_bits: Option<usize> = env::var("PIR_PLAINTEXT_BITS")
+    .ok()
+    .map(|v| v.parse().unwrap());
   CLIFlags {
     matrix_height,
     lwe_dim,
@@ -1,11 +1,12 @@
 /// The `api` module is the public entry point for all FrodoPIR database.
 use crate::db::Database;
-pub use crate::db::{BaseParams, CommonParams};
+pub use crate::db::{BaseParams, CommonParams, DbDimensions};
 use crate::errors::{
   ErrorOverflownAdd, ErrorQueryParamsReused, ResultBoxedError,
 };
 pub use crate::utils::format::*;
 use crate::utils::lwe::*;
+pub use crate::utils::lwe::{recommended_plaintext_bits, RECOMMENDED_LWE_DIM};
 use crate::utils::matrices::*;
 use serde::{Deserialize, Serialize};
 use std::fs;
@@ -22,8 +23,10 @@ pub struct Shard {
 impl Shard {
   /// Expects a JSON file of base64-encoded strings in file path. It also
   /// expects the lwe dimension, m (the number of DB elements), element size
-  /// (in bytes) of the database elements, and plaintext bits.
-  /// It will call the 'from_base64_strings' function to generate the database.
+  /// (in bits) of the database elements, and plaintext bits.
+  /// It will call the 'from_base64_strings' function to generate the database,
+  /// so the file must contain exactly m elements. Returns an error if the
+  /// file cannot be read or parsed.
   pub fn from_json_file(
     file_path: &str,
     lwe_dim: usize,
@@ -31,14 +34,15 @@ impl Shard {
     elem_size: usize,
     plaintext_bits: usize,
   ) -> ResultBoxedError<Self> {
-    let file_contents: String =
-      fs::read_to_string(file_path).unwrap().parse().unwrap();
-    let elements: Vec<String> = serde_json::from_str(&file_contents).unwrap();
+    let file_contents: String = fs::read_to_string(file_path)?.parse()?;
+    let elements: Vec<String> = serde_json::from_str(&file_contents)?;
     Shard::from_base64_strings(&elements, lwe_dim, m, elem_size, plaintext_bits)
   }
 
   /// Expects an array of base64-encoded strings and converts into a
-  /// database that can process client queries
+  /// database that can process client queries. Returns an error if the
+  /// number of strings is not exactly m, or if any decoded element is
+  /// larger than the element size.
   pub fn from_base64_strings(
     base64_strs: &[String],
     lwe_dim: usize,
@@ -51,6 +55,27 @@ impl Shard {
     Ok(Self { db, base_params })
   }
 
+  /// Expects an array of base64-encoded strings and the dimensions derived
+  /// from them (see `DbDimensions::from_base64_strings`), and converts into
+  /// a database that can process client queries. The database is padded
+  /// with empty elements up to the matrix height.
+  pub fn from_base64_strings_with_dims(
+    base64_strs: &[String],
+    dims: &DbDimensions,
+  ) -> ResultBoxedError<Self> {
+    let mut elements = base64_strs.to_vec();
+    if elements.len() < dims.get_matrix_height() {
+      elements.resize(dims.get_matrix_height(), String::new());
+    }
+    Shard::from_base64_strings(
+      &elements,
+      dims.get_dim(),
+      dims.get_matrix_height(),
+      dims.get_elem_size(),
+      dims.get_plaintext_bits(),
+    )
+  }
+
   /// Write base_params and DB to file
   pub fn write_to_file(
     &self,
@@ -262,6 +287,174 @@ mod tests {
     assert!(res.is_err());
   }
 
+  #[test]
+  fn client_query_with_base_params_from_file() {
+    let m = 2u32.pow(6) as usize;
+    let elem_size = 2u32.pow(8) as usize;
+    let plaintext_bits = 10usize;
+    let lwe_dim = 512;
+    let db_elems = generate_db_elems(m, (elem_size + 7) / 8);
+    let shard = Shard::from_base64_strings(
+      &db_elems,
+      lwe_dim,
+      m,
+      elem_size,
+      plaintext_bits,
+    )
+    .unwrap();
+
+    // the client only has access to the params written by the server
+    let params_path = std::env::temp_dir().join(format!(
+      "frodo_pir_base_params_{}_{}.json",
+      std::process::id(),
+      OsRng.next_u64(),
+    ));
+    let params_path = params_path.to_str().unwrap();
+    shard.get_base_params().write_to_file(params_path).unwrap();
+    let res = BaseParams::load(params_path);
+    fs::remove_file(params_path).unwrap();
+    let bp = res.unwrap();
+    let cp = CommonParams::from(&bp);
+
+    let mut qp = QueryParams::new(&cp, &bp).unwrap();
+    let q = qp.generate_query(1).unwrap();
+    let d_resp = shard.respond(&q).unwrap();
+    let resp: Response = bincode::deserialize(&d_resp).unwrap();
+    assert_eq!(resp.parse_output_as_base64(&qp), db_elems[1]);
+  }
+
+  #[test]
+  fn shard_from_base64_strings_bad_sizes() {
+    let m = 2u32.pow(6) as usize;
+    let elem_size = 2u32.pow(8) as usize;
+    let plaintext_bits = 10usize;
+    let lwe_dim = 512;
+
+    // fewer elements than the matrix height
+    let db_elems = generate_db_elems(m - 1, (elem_size + 7) / 8);
+    let res = Shard::from_base64_strings(
+      &db_elems,
+      lwe_dim,
+      m,
+      elem_size,
+      plaintext_bits,
+    );
+    assert!(res.is_err());
+
+    // more elements than the matrix height
+    let db_elems = generate_db_elems(m + 1, (elem_size + 7) / 8);
+    let res = Shard::from_base64_strings(
+      &db_elems,
+      lwe_dim,
+      m,
+      elem_size,
+      plaintext_bits,
+    );
+    assert!(res.is_err());
+
+    // elements longer than the element size
+    let db_elems = generate_db_elems(m, (elem_size + 7) / 8 + 1);
+    let res = Shard::from_base64_strings(
+      &db_elems,
+      lwe_dim,
+      m,
+      elem_size,
+      plaintext_bits,
+    );
+    assert!(res.is_err());
+
+    // elements with bits set beyond an element size that is not a
+    // multiple of 8
+    let elem_size = elem_size - 4;
+    let db_elems = vec![base64::encode(vec![0xFFu8; (elem_size + 7) / 8]); m];
+    let res = Shard::from_base64_strings(
+      &db_elems,
+      lwe_dim,
+      m,
+      elem_size,
+      plaintext_bits,
+    );
+    assert!(res.is_err());
+  }
+
+  #[test]
+  fn recommended_plaintext_bits_edges() {
+    assert_eq!(recommended_plaintext_bits(2usize.pow(18)), Some(10));
+    assert_eq!(recommended_plaintext_bits(2usize.pow(18) + 1), Some(9));
+    assert_eq!(recommended_plaintext_bits(2usize.pow(20)), Some(9));
+    assert_eq!(recommended_plaintext_bits(2usize.pow(20) + 1), None);
+  }
+
+  #[test]
+  fn client_query_with_derived_dimensions() {
+    let mut db_elems = generate_db_elems(48, 32);
+    db_elems.extend(generate_db_elems(2, 16));
+
+    let dims = DbDimensions::from_base64_strings(&db_elems).unwrap();
+    let elem_size = dims.get_elem_size();
+    assert_eq!(dims.get_dim(), RECOMMENDED_LWE_DIM);
+    assert_eq!(dims.get_matrix_height(), 64);
+    assert_eq!(dims.get_matrix_height_exp(), 6);
+    assert_eq!(elem_size, 256);
+    assert_eq!(dims.get_plaintext_bits(), 10);
+
+    let shard = Shard::from_base64_strings_with_dims(&db_elems, &dims).unwrap();
+    let bp = shard.get_base_params();
+    let cp = CommonParams::from(bp);
+
+    // shorter elements, and the padding, are returned as zero bytes
+    let mut short_elem = base64::decode(&db_elems[48]).unwrap();
+    short_elem.resize(elem_size / 8, 0);
+    for (i, expected) in [
+      (0, db_elems[0].clone()),
+      (48, base64::encode(short_elem)),
+      (60, base64::encode(vec![0u8; elem_size / 8])),
+    ] {
+      let mut qp = QueryParams::new(&cp, bp).unwrap();
+      let q = qp.generate_query(i).unwrap();
+      let d_resp = shard.respond(&q).unwrap();
+      let resp: Response = bincode::deserialize(&d_resp).unwrap();
+      assert_eq!(resp.parse_output_as_base64(&qp), expected);
+    }
+  }
+
+  #[test]
+  fn db_dimensions_write_and_load() {
+    let db_elems = generate_db_elems(100, 32);
+    let dims = DbDimensions::from_base64_strings(&db_elems).unwrap();
+
+    let dims_path = std::env::temp_dir().join(format!(
+      "frodo_pir_db_dimensions_{}_{}.json",
+      std::process::id(),
+      OsRng.next_u64(),
+    ));
+    let dims_path = dims_path.to_str().unwrap();
+    dims.write_to_file(dims_path).unwrap();
+    let res = DbDimensions::load(dims_path);
+    fs::remove_file(dims_path).unwrap();
+    let loaded = res.unwrap();
+
+    assert_eq!(loaded.get_dim(), dims.get_dim());
+    assert_eq!(loaded.get_matrix_height(), 128);
+    assert_eq!(loaded.get_matrix_height(), dims.get_matrix_height());
+    assert_eq!(loaded.get_elem_size(), dims.get_elem_size());
+    assert_eq!(loaded.get_plaintext_bits(), dims.get_plaintext_bits());
+  }
+
+  #[test]
+  fn db_dimensions_bad_sizes() {
+    // no elements
+    assert!(DbDimensions::from_base64_strings(&[]).is_err());
+
+    // only empty elements
+    let db_elems = vec![String::new(); 4];
+    assert!(DbDimensions::from_base64_strings(&db_elems).is_err());
+
+    // more elements than supported by the parameters
+    let db_elems = vec![String::from("AA=="); 2usize.pow(20) + 1];
+    assert!(DbDimensions::from_base64_strings(&db_elems).is_err());
+  }
+
   // This will generate random elements for test databases
   fn generate_db_elems(num_elems: usize, elem_byte_len: usize) -> Vec<String> {
     let mut elems = Vec::with_capacity(num_elems);
@@ -5,8 +5,9 @@ use rand_core::{OsRng, RngCore};
 use serde::{Deserialize, Serialize};
 use serde_json::json;
 
-use crate::errors::ResultBoxedError;
+use crate::errors::{ErrorUnexpectedInputSize, ResultBoxedError};
 use crate::utils::format::*;
+use crate::utils::lwe::{recommended_plaintext_bits, RECOMMENDED_LWE_DIM};
 use crate::utils::matrices::*;
 
 #[derive(Clone, Debug, Serialize, Deserialize)]
@@ -159,13 +160,11 @@ impl BaseParams {
       .collect()
   }
 
-  /// Writes the params struct as JSON to file
+  /// Writes the params struct as JSON to file, in the format expected by
+  /// `BaseParams::load`. Only the public seed is written for the LHS, the
+  /// client derives the matrix itself using `CommonParams::from`.
   pub fn write_to_file(&self, path: &str) -> ResultBoxedError<()> {
-    let json = json!({
-      "public_seed": self.public_seed,
-      "rhs": self.rhs,
-    });
-    Ok(serde_json::to_writer(&fs::File::create(path)?, &json)?)
+    Ok(serde_json::to_writer(&fs::File::create(path)?, self)?)
   }
 
   /// Computes c = s*(A*DB) using the RHS of the public parameters
@@ -226,25 +225,138 @@ impl From<&BaseParams> for CommonParams {
   }
 }
 
+/// `DbDimensions` holds the dimensions of a DB matrix that accommodates a
+/// given set of DB elements, for use when generating the server params.
+/// Note that the values are not exponents: the matrix height is a power of
+/// two (see `get_matrix_height_exp`), but the element size generally is not,
+/// so it must be used as is rather than through the `PIR_ELEM_SIZE_EXP`
+/// benchmark setting.
+#[derive(Serialize, Deserialize, Clone, Debug)]
+pub struct DbDimensions {
+  dim: usize,       // the lwe dimension
+  m: usize,         // the height of the DB matrix
+  elem_size: usize, // the size (in bits) of each element of the DB
+  plaintext_bits: usize,
+}
+
+impl DbDimensions {
+  /// Derives the dimensions from an array of base64-encoded strings. The
+  /// height is the next power of two from the number of elements, and the
+  /// element size is that of the longest element. Returns an error if the
+  /// elements are too many for the parameters in the paper.
+  pub fn from_base64_strings(elements: &[String]) -> ResultBoxedError<Self> {
+    let m = elements.len().next_power_of_two();
+    let plaintext_bits = match recommended_plaintext_bits(m) {
+      Some(bits) if !elements.is_empty() => bits,
+      _ => {
+        return Err(Box::new(ErrorUnexpectedInputSize::new(format!(
+          "no parameters for a DB with {} elements",
+          elements.len(),
+        ))))
+      }
+    };
+    let mut elem_byte_len = 0usize;
+    for data in elements {
+      elem_byte_len = elem_byte_len.max(base64::decode(data)?.len());
+    }
+    if elem_byte_len == 0 {
+      return Err(Box::new(ErrorUnexpectedInputSize::new(
+        "all DB elements are empty".to_string(),
+      )));
+    }
+    Ok(Self {
+      dim: RECOMMENDED_LWE_DIM,
+      m,
+      elem_size: elem_byte_len * 8,
+      plaintext_bits,
+    })
+  }
+
+  /// Load dimensions from a JSON file
+  pub fn load(path: &str) -> ResultBoxedError<Self> {
+    let reader = BufReader::new(fs::File::open(path)?);
+    Ok(serde_json::from_reader(reader)?)
+  }
+
+  /// Writes the dimensions as JSON to file
+  pub fn write_to_file(&self, path: &str) -> ResultBoxedError<()> {
+    Ok(serde_json::to_writer(&fs::File::create(path)?, self)?)
+  }
+
+  pub fn get_dim(&self) -> usize {
+    self.dim
+  }
+
+  pub fn get_matrix_height(&self) -> usize {
+    self.m
+  }
+
+  /// Returns log2 of the matrix height, as expected by the benchmarks
+  pub fn get_matrix_height_exp(&self) -> usize {
+    self.m.trailing_zeros() as usize
+  }
+
+  pub fn get_elem_size(&self) -> usize {
+    self.elem_size
+  }
+
+  pub fn get_plaintext_bits(&self) -> usize {
+    self.plaintext_bits
+  }
+}
+
 fn construct_rows(
   elements: &[String],
   m: usize,
   elem_size: usize,
   plaintext_bits: usize,
 ) -> ResultBoxedError<Vec<Vec<u32>>> {
+  // every element must fit in the DB matrix, and every row must be set
+  if elements.len() != m {
+    return Err(Box::new(ErrorUnexpectedInputSize::new(format!(
+      "number of elements: {}, expected: {}",
+      elements.len(),
+      m,
+    ))));
+  }
   let row_width = Database::get_matrix_width(elem_size, plaintext_bits);
+  let elem_byte_len = (elem_size + 7) / 8;
 
   let result = (0..m).map(|i| -> ResultBoxedError<Vec<u32>> {
     let mut row = Vec::with_capacity(row_width);
     let data = &elements[i];
     let bytes = base64::decode(data)?;
+    if bytes.len() > elem_byte_len {
+      return Err(Box::new(ErrorUnexpectedInputSize::new(format!(
+        "element {} byte length: {}, expected at most: {}",
+        i,
+        bytes.len(),
+        elem_byte_len,
+      ))));
+    }
+    // bits of the last byte beyond the element size must not be set, as
+    // they would be dropped from the DB
+    let rem_bits = elem_size % 8;
+    if rem_bits != 0
+      && bytes.len() == elem_byte_len
+      && bytes[elem_byte_len - 1] >> rem_bits != 0
+    {
+      return Err(Box::new(ErrorUnexpectedInputSize::new(format!(
+        "element {} has bits set beyond the element size: {}",
+        i, elem_size,
+      ))));
+    }
     let bits = bytes_to_bits_le(&bytes);
     for i in 0..row_width {
+      let start_bound = i * plaintext_bits;
       let end_bound = (i + 1) * plaintext_bits;
       if end_bound < bits.len() {
-        row.push(bits_to_u32_le(&bits[i * plaintext_bits..end_bound])?);
+        row.push(bits_to_u32_le(&bits[start_bound..end_bound])?);
+      } else if start_bound < bits.len() {
+        row.push(bits_to_u32_le(&bits[start_bound..])?);
       } else {
-        row.push(bits_to_u32_le(&bits[i * plaintext_bits..])?);
+        // elements shorter than the element size are padded with zeroes
+        row.push(0);
       }
     }
     Ok(row)
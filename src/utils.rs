@@ -5,6 +5,10 @@
 pub mod lwe {
   const MODULUS: u64 = u32::MAX as u64 + 1;
 
+  /// The LWE dimension used for the parameters in Section 5 of the paper,
+  /// and for the benchmarks in the Makefile.
+  pub const RECOMMENDED_LWE_DIM: usize = 1774;
+
   /// Returns a value indicating the indicator value which is used to reveal
   /// the DB row that is queried.
   pub fn get_rounding_factor(plaintext_bits: usize) -> u32 {
@@ -21,6 +25,21 @@ pub mod lwe {
   pub fn get_plaintext_size(plaintext_bits: usize) -> u32 {
     2u32.pow(plaintext_bits as u32)
   }
+
+  /// Returns the number of plaintext bits to encode in each entry of a DB
+  /// matrix of the given height, following Section 5 of the paper:
+  ///   - 10 bits, for log2(m) ≤ 18
+  ///   - 9 bits, for log2(m) ≤ 20
+  /// Larger matrices are not supported by the parameters in the paper.
+  pub fn recommended_plaintext_bits(matrix_height: usize) -> Option<usize> {
+    if matrix_height <= 2_usize.pow(18) {
+      Some(10)
+    } else if matrix_height <= 2_usize.pow(20) {
+      Some(9)
+    } else {
+      None
+    }
+  }
 }
 
 /// Functionality for matrix and vector manipulation